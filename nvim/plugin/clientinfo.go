@@ -0,0 +1,112 @@
+package plugin
+
+import (
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/neovim/go-client/nvim"
+)
+
+// ClientInfo overrides the fields Main would otherwise derive
+// automatically (from build info and the handlers registered on the
+// Plugin) before reporting the application's identity to Nvim via
+// nvim_set_client_info. Fields left at their zero value keep the
+// derived value.
+type ClientInfo struct {
+	Name       string
+	Version    nvim.ClientVersion
+	Attributes nvim.ClientAttributes
+}
+
+// getClientInfo derives a nvim.Client describing this plugin: Name and
+// Version from the module's build info, and Methods from the handlers
+// p has registered, so nvim_set_client_info reports accurate data
+// instead of the empty placeholders Main used to send.
+func getClientInfo(p *Plugin, kind string) *nvim.Client {
+	name := "go-" + kind
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Path != "" {
+		name = info.Main.Path
+	}
+	version, buildTime := buildVersion()
+	attrs := nvim.ClientAttributes{
+		"license": "Apache v2",
+		"website": "github.com/neovim/go-client",
+	}
+	if buildTime != "" {
+		attrs["build_time"] = buildTime
+	}
+	return &nvim.Client{
+		Name:       name,
+		Version:    version,
+		Methods:    handlerMethods(p),
+		Attributes: attrs,
+	}
+}
+
+// buildVersion derives a ClientVersion from runtime/debug.ReadBuildInfo,
+// falling back to the zero value when build info isn't available (for
+// example, a binary built with -trimpath -buildvcs=false or run under
+// `go run`). The VCS commit timestamp, if any, is returned separately
+// since it isn't a semver prerelease identifier and so doesn't belong
+// in ClientVersion.Prerelease.
+func buildVersion() (version nvim.ClientVersion, buildTime string) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return version, ""
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		version.Major, version.Minor, version.Patch = parseSemver(v)
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			version.Commit = setting.Value
+		case "vcs.time":
+			buildTime = setting.Value
+		}
+	}
+	return version, buildTime
+}
+
+// parseSemver extracts the numeric major.minor.patch components from a
+// Go module version string such as "v1.2.3".
+func parseSemver(v string) (major, minor, patch int) {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.SplitN(v, ".", 3)
+	if len(parts) > 0 {
+		major, _ = strconv.Atoi(parts[0])
+	}
+	if len(parts) > 1 {
+		minor, _ = strconv.Atoi(parts[1])
+	}
+	if len(parts) > 2 {
+		patch, _ = strconv.Atoi(parts[2])
+	}
+	return major, minor, patch
+}
+
+// handlerMethods enumerates p's registered commands, autocmds and
+// functions via specsFromManifest, so the reported methods always
+// match what Nvim will actually see registered for this plugin.
+// Spec.Opts (nargs, pattern, ...) has no counterpart in
+// nvim.ClientMethod, which only reports whether a method is async;
+// it matters for Host.specs, which builds the actual
+// remote#host#RegisterPlugin call, not here.
+func handlerMethods(p *Plugin) map[string]*nvim.ClientMethod {
+	methods := map[string]*nvim.ClientMethod{}
+	if p == nil {
+		return methods
+	}
+	for _, spec := range specsFromManifest(p.Manifest("client")) {
+		key := spec.Name
+		if spec.Type == "autocmd" {
+			key = "autocmd:" + spec.Name
+		}
+		methods[key] = &nvim.ClientMethod{Async: !spec.Sync}
+	}
+	return methods
+}
@@ -0,0 +1,28 @@
+package plugin
+
+import "testing"
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		version             string
+		major, minor, patch int
+	}{
+		{"v1.2.3", 1, 2, 3},
+		{"v1.2.3-rc.1", 1, 2, 3},
+		{"v1.2.3+build", 1, 2, 3},
+		{"v2.0.0", 2, 0, 0},
+	}
+	for _, tt := range tests {
+		major, minor, patch := parseSemver(tt.version)
+		if major != tt.major || minor != tt.minor || patch != tt.patch {
+			t.Errorf("parseSemver(%q) = %d.%d.%d, want %d.%d.%d",
+				tt.version, major, minor, patch, tt.major, tt.minor, tt.patch)
+		}
+	}
+}
+
+func TestHandlerMethods(t *testing.T) {
+	if methods := handlerMethods(nil); len(methods) != 0 {
+		t.Errorf("handlerMethods(nil) = %v, want empty map", methods)
+	}
+}
@@ -0,0 +1,135 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/neovim/go-client/nvim"
+)
+
+// Host implements the poll, specs and Path RPC endpoints that Nvim's
+// remote#host#Register expects from a host process (the same contract
+// satisfied by the python3 and node hosts). Registering a Host on a
+// Nvim client turns a Go binary into a real remote plugin host, so
+// :UpdateRemotePlugins can discover every plugin under rplugin/go in
+// the runtimepath instead of the manifest being hand-edited into a
+// Vimscript file.
+type Host struct {
+	vim     *nvim.Nvim
+	plugins map[string]bool // discovered rplugin/go/* paths, populated by Register
+}
+
+// NewHost creates a Host bound to vim. Call Register before vim.Serve
+// so remote#host#Register can complete its handshake.
+func NewHost(vim *nvim.Nvim) *Host {
+	return &Host{vim: vim}
+}
+
+// Register discovers the plugins under rplugin/go in vim's runtimepath
+// and installs the poll, specs and Path handlers on the underlying
+// Nvim client.
+func (h *Host) Register() error {
+	rtp, err := h.runtimepath()
+	if err != nil {
+		return fmt.Errorf("plugin: reading runtimepath: %w", err)
+	}
+	plugins, err := DiscoverPlugins(rtp)
+	if err != nil {
+		return fmt.Errorf("plugin: discovering rplugin/go plugins: %w", err)
+	}
+	h.plugins = make(map[string]bool, len(plugins))
+	for _, p := range plugins {
+		h.plugins[p] = true
+	}
+
+	if err := h.vim.RegisterHandler("poll", h.poll); err != nil {
+		return err
+	}
+	if err := h.vim.RegisterHandler("specs", h.specs); err != nil {
+		return err
+	}
+	if err := h.vim.RegisterHandler("Path", h.path); err != nil {
+		return err
+	}
+	return nil
+}
+
+// runtimepath reads Nvim's 'runtimepath' option.
+func (h *Host) runtimepath() ([]string, error) {
+	var rtp string
+	if err := h.vim.Eval("&runtimepath", &rtp); err != nil {
+		return nil, err
+	}
+	return strings.Split(rtp, ","), nil
+}
+
+// poll answers Nvim's handshake request. See :h remote#host#Register.
+func (h *Host) poll() (string, error) {
+	return "ok", nil
+}
+
+// path reports the location of the running host binary, matching the
+// path argument of remote#host#RegisterPlugin.
+func (h *Host) path() (string, error) {
+	return os.Executable()
+}
+
+// specs loads the plugin binary at path and returns the handler specs
+// Nvim needs to build its own call remote#host#RegisterPlugin block
+// during :UpdateRemotePlugins. The return value is a plain slice of
+// maps, not the rendered Vimscript text, since Nvim's specs RPC
+// expects a msgpack list it can iterate directly.
+func (h *Host) specs(path string) ([]interface{}, error) {
+	if !h.plugins[path] {
+		return nil, fmt.Errorf("plugin: %s is not a discovered rplugin/go plugin", path)
+	}
+	manifest, err := loadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	specs := specsFromManifest(manifest)
+	dicts := make([]interface{}, len(specs))
+	for i, spec := range specs {
+		dicts[i] = spec.vimDict()
+	}
+	return dicts, nil
+}
+
+// DiscoverPlugins walks rtp looking for rplugin/go/* entries. Entries
+// are expected to be executables built with `go build`, one per
+// plugin, the same layout Nvim already uses for rplugin/python3 and
+// rplugin/node.
+func DiscoverPlugins(rtp []string) ([]string, error) {
+	var plugins []string
+	for _, dir := range rtp {
+		matches, err := filepath.Glob(filepath.Join(dir, "rplugin", "go", "*"))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+				continue
+			}
+			plugins = append(plugins, match)
+		}
+	}
+	return plugins, nil
+}
+
+// loadManifest spawns the plugin binary at path with --manifest=host
+// and returns its stdout, the manifest for that plugin.
+func loadManifest(path string) ([]byte, error) {
+	cmd := exec.Command(path, "--manifest=host")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("plugin: loading manifest from %s: %w", path, err)
+	}
+	return stdout.Bytes(), nil
+}
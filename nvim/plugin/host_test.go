@@ -0,0 +1,39 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiscoverPlugins(t *testing.T) {
+	dir := t.TempDir()
+	rplugin := filepath.Join(dir, "rplugin", "go")
+	if err := os.MkdirAll(rplugin, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	exe := filepath.Join(rplugin, "myplugin")
+	if err := os.WriteFile(exe, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	notExe := filepath.Join(rplugin, "README.md")
+	if err := os.WriteFile(notExe, []byte("not a plugin"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := DiscoverPlugins([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != exe {
+		t.Errorf("DiscoverPlugins(%q) = %v, want [%s]", dir, got, exe)
+	}
+}
+
+func TestHostSpecsRejectsUndiscoveredPath(t *testing.T) {
+	h := &Host{plugins: map[string]bool{"/rtp/rplugin/go/known": true}}
+	if _, err := h.specs("/tmp/not-discovered"); err == nil {
+		t.Error("specs() on an undiscovered path = nil error, want an error")
+	}
+}
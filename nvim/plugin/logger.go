@@ -0,0 +1,49 @@
+package plugin
+
+import (
+	"fmt"
+	"log"
+)
+
+// Logger is implemented by structured logging libraries such as
+// zerolog, hclog or zap. Main logs through a Logger instead of calling
+// log.Printf directly so those libraries can be plugged in via
+// Options.Logger.
+//
+// Each method takes a message followed by alternating key-value pairs,
+// mirroring the convention used by hclog and the stdlib slog package.
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+// stdLogger adapts the standard library log package to the Logger
+// interface. It is the default used by Main when Options.Logger is nil.
+type stdLogger struct {
+	*log.Logger
+}
+
+// NewStdLogger returns a Logger that writes to l, rendering key-value
+// pairs inline after the message.
+func NewStdLogger(l *log.Logger) Logger {
+	return &stdLogger{Logger: l}
+}
+
+func (l *stdLogger) Debug(msg string, keyvals ...interface{}) { l.log("DEBUG", msg, keyvals) }
+func (l *stdLogger) Info(msg string, keyvals ...interface{})  { l.log("INFO", msg, keyvals) }
+func (l *stdLogger) Warn(msg string, keyvals ...interface{})  { l.log("WARN", msg, keyvals) }
+func (l *stdLogger) Error(msg string, keyvals ...interface{}) { l.log("ERROR", msg, keyvals) }
+
+func (l *stdLogger) log(level, msg string, keyvals []interface{}) {
+	l.Printf("%s %s", level, formatKeyvals(msg, keyvals))
+}
+
+func formatKeyvals(msg string, keyvals []interface{}) string {
+	s := msg
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		s += fmt.Sprintf(" %v=%v", keyvals[i], keyvals[i+1])
+	}
+	return s
+}
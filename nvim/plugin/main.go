@@ -4,15 +4,49 @@ package plugin
 import (
 	"bytes"
 	"flag"
-	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"regexp"
 
 	"github.com/neovim/go-client/nvim"
 )
 
+// Options holds optional settings for Main.
+type Options struct {
+	// Logger receives Main's log output, including the Nvim client's RPC
+	// traces. A trace is logged with its rendered message, plus "duration"
+	// and "error" fields when it carries a time.Duration or an error; the
+	// method and msgid are not logged as separate fields. If nil, Main
+	// uses a Logger backed by the standard log package.
+	Logger Logger
+
+	// ClientInfo overrides the Name, Version and Attributes Main would
+	// otherwise derive from build info and the registered handlers.
+	ClientInfo *ClientInfo
+}
+
+// applyClientInfoOverrides copies any non-zero field of override onto
+// client, leaving the derived values in place otherwise.
+func applyClientInfoOverrides(client *nvim.Client, override *ClientInfo) {
+	if override == nil {
+		return
+	}
+	if override.Name != "" {
+		client.Name = override.Name
+	}
+	if (override.Version != nvim.ClientVersion{}) {
+		client.Version = override.Version
+	}
+	for k, v := range override.Attributes {
+		if client.Attributes == nil {
+			client.Attributes = nvim.ClientAttributes{}
+		}
+		client.Attributes[k] = v
+	}
+}
+
 // Main implements the main function for a Nvim remote plugin.
 //
 // Plugin applications call the Main function to run the plugin. The Main
@@ -20,23 +54,57 @@ import (
 // handlers with the plugin and then runs the server loop to handle requests
 // from Nvim.
 //
-// Applications should use the default logger in the standard log package to
-// write to Nvim's log.
+// By default Main logs through the standard log package. Pass an Options
+// with a Logger set to plug in a structured logging library such as
+// zerolog, hclog or zap instead.
 //
 // Run the plugin application with the command line option --manifest=hostName
 // to print the plugin manifest to stdout. Add the manifest manually to a
-// Vimscript file. The :UpdateRemotePlugins command is not supported at this
-// time.
+// Vimscript file, or run the application with --host to serve as a proper
+// Nvim remote plugin host, in which case :UpdateRemotePlugins discovers and
+// registers the plugin automatically.
 //
 // If the --manifest=host command line flag is specified, then Main prints the
 // plugin manifest to stdout insead of running the application as a plugin.
 // If the --location=vimfile command line flag is specified, then plugin
 // manifest will be automatically written to .vim file.
-func Main(registerHandlers func(p *Plugin) error) {
+//
+// If the --host command line flag is specified, then Main registers a Host
+// in addition to the plugin's own handlers, answering the poll, specs and
+// Path RPCs that remote#host#Register requires.
+//
+// If the --requirements command line flag, or the NVIM_GO_PLUGIN_REQUIREMENTS
+// environment variable, is set, then Main prints the JSON document described
+// by Requirements to stdout instead of running the application as a plugin.
+func Main(registerHandlers func(p *Plugin) error, opts ...Options) {
+	var opt Options
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	logger := opt.Logger
+	if logger == nil {
+		logger = NewStdLogger(log.New(os.Stderr, "", 0))
+	}
+
 	pluginHost := flag.String("manifest", "", "Write plugin manifest for `host` to stdout")
 	vimFilePath := flag.String("location", "", "Manifest is automatically written to `.vim file`")
+	asHost := flag.Bool("host", false, "Serve as a Nvim remote plugin host for :UpdateRemotePlugins")
+	dumpReqs := flag.Bool("requirements", os.Getenv("NVIM_GO_PLUGIN_REQUIREMENTS") == "1", "Write plugin requirements as JSON to stdout")
 	flag.Parse()
 
+	if *dumpReqs {
+		log.SetFlags(0)
+		// Requirements are registered by init() calls to
+		// RegisterRequirement, independent of registerHandlers, so
+		// there's no need to run handler registration just to dump them.
+		doc, err := dumpRequirements()
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Stdout.Write(doc)
+		return
+	}
+
 	if *pluginHost != "" {
 		log.SetFlags(0)
 		p := New(nil)
@@ -45,7 +113,7 @@ func Main(registerHandlers func(p *Plugin) error) {
 		}
 		manifest := p.Manifest(*pluginHost)
 		if *vimFilePath != "" {
-			if err := overwriteManifest(*vimFilePath, *pluginHost, manifest); err != nil {
+			if err := RewriteManifest(*vimFilePath, *pluginHost, manifest); err != nil {
 				log.Fatal(err)
 			}
 		} else {
@@ -58,7 +126,7 @@ func Main(registerHandlers func(p *Plugin) error) {
 	os.Stdout = os.Stderr
 	log.SetFlags(0)
 
-	v, err := nvim.New(os.Stdin, stdout, stdout, log.Printf)
+	v, err := nvim.New(os.Stdin, stdout, stdout, rpcLogf(logger))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -68,62 +136,124 @@ func Main(registerHandlers func(p *Plugin) error) {
 		log.Fatal(err)
 	}
 
+	if *asHost {
+		if err := NewHost(v).Register(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	quit := make(chan error, 1)
 	go func() {
 		quit <- v.Serve()
 	}()
 
-	client := getClientInfo("client")
+	client := getClientInfo(p, "client")
+	applyClientInfoOverrides(client, opt.ClientInfo)
 	if err := v.SetClientInfo(
 		client.Name, &client.Version, "remote", client.Methods, client.Attributes); err != nil {
 		log.Fatal(err)
 	}
 
+	logger.Info("serving plugin", "name", client.Name)
 	err = <-quit
 	if err != nil {
+		logger.Error("plugin exited", "error", err)
 		log.Fatal(err)
 	}
 }
 
-func getClientInfo(kind string) *nvim.Client {
-	// TODO: fill in the blank
-	return &nvim.Client{
-		Name:    fmt.Sprintf("go-%s", kind),
-		Version: nvim.ClientVersion{},
-		Methods: map[string]*nvim.ClientMethod{},
-		Attributes: nvim.ClientAttributes{
-			"license": "Apache v2",
-			"website": "github.com/neovim/go-client",
-		},
+// manifestBeginMarker and manifestEndMarker delimit the block of a
+// single host's generated manifest inside a shared Vimscript file, so
+// RewriteManifest can find and replace exactly its own block even when
+// several hosts' manifests live in the same file.
+const (
+	manifestBeginMarker = `" >>> go-client manifest: `
+	manifestEndMarker   = `" <<<`
+)
+
+// RewriteManifest rewrites the manifest block for host inside the file
+// at path, leaving every other host's block untouched. The block is
+// delimited by sentinel comments rather than matched against the exact
+// quoting of `call remote#host#RegisterPlugin(...)`, so a change in how
+// Nvim renders that call can't corrupt the file. The whole
+// read-modify-write cycle runs under a lock obtained from lockManifest,
+// so two hosts rewriting the same file concurrently serialize instead
+// of one silently clobbering the other's just-written block, and the
+// write itself goes through a temp file plus os.Rename so a concurrent
+// reader never sees a partially written manifest. If path doesn't
+// exist yet, it is created with mode 0644; otherwise the original
+// file's mode is kept.
+func RewriteManifest(path, host string, manifest []byte) error {
+	unlock, err := lockManifest(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	input, mode, err := readManifestFile(path)
+	if err != nil {
+		return err
+	}
+	output := replaceManifestBlock(host, input, manifest)
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once the rename below succeeds
+
+	if _, err := tmp.Write(output); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
 	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
 }
 
-func overwriteManifest(path, host string, manifest []byte) error {
+// readManifestFile reads path, returning its current contents and
+// mode. A missing file reads as empty content with mode 0644.
+func readManifestFile(path string) ([]byte, os.FileMode, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, 0644, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
 	input, err := ioutil.ReadFile(path)
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
-	output := replaceManifest(host, input, manifest)
-	return ioutil.WriteFile(path, output, 0666)
+	return input, info.Mode(), nil
 }
 
-func replaceManifest(host string, input, manifest []byte) []byte {
-	p := regexp.MustCompile(`(?ms)^call remote#host#RegisterPlugin\('` + regexp.QuoteMeta(host) + `'.*?^\\ ]\)$`)
+// replaceManifestBlock returns input with host's sentinel-delimited
+// manifest block replaced by manifest, appending a new block at the
+// end of the file if host doesn't have one yet.
+func replaceManifestBlock(host string, input, manifest []byte) []byte {
+	begin := []byte(manifestBeginMarker + host + "\n")
+	block := append(append([]byte{}, begin...), manifest...)
+	if !bytes.HasSuffix(block, []byte("\n")) {
+		block = append(block, '\n')
+	}
+	block = append(block, []byte(manifestEndMarker+"\n")...)
+
+	p := regexp.MustCompile(`(?ms)^` + regexp.QuoteMeta(manifestBeginMarker+host) + `\n.*?^` + regexp.QuoteMeta(manifestEndMarker) + `\n`)
 	match := p.FindIndex(input)
-	var output []byte
 	if match == nil {
 		if len(input) > 0 && input[len(input)-1] != '\n' {
 			input = append(input, '\n')
 		}
-		output = append(input, manifest...)
-	} else {
-		if match[1] != len(input) {
-			// No need for trailing \n if in middle of file.
-			manifest = bytes.TrimSuffix(manifest, []byte{'\n'})
-		}
-		output = append([]byte{}, input[:match[0]]...)
-		output = append(output, manifest...)
-		output = append(output, input[match[1]:]...)
+		return append(input, block...)
 	}
+	output := append([]byte{}, input[:match[0]]...)
+	output = append(output, block...)
+	output = append(output, input[match[1]:]...)
 	return output
 }
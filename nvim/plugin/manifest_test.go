@@ -0,0 +1,121 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRewriteManifestCreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plugin.vim")
+
+	if err := RewriteManifest(path, "client", []byte("\\ {'type': 'command', 'name': 'Hello', 'sync': 1, 'opts': {}},\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "Hello") {
+		t.Errorf("manifest file = %q, want it to contain the written block", got)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("new manifest file mode = %v, want 0644", info.Mode())
+	}
+}
+
+func TestRewriteManifestPreservesModeAndOtherHosts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plugin.vim")
+	if err := os.WriteFile(path, []byte("\" some preexisting vimscript\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RewriteManifest(path, "python3", []byte("\\ {'type': 'command', 'name': 'Py', 'sync': 1, 'opts': {}},\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := RewriteManifest(path, "go-client", []byte("\\ {'type': 'command', 'name': 'Go', 'sync': 1, 'opts': {}},\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"some preexisting vimscript", "Py", "Go"} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("manifest file = %q, want it to still contain %q", got, want)
+		}
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("manifest file mode = %v, want preserved 0600", info.Mode())
+	}
+}
+
+func TestRewriteManifestIsIdempotent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plugin.vim")
+	block := []byte("\\ {'type': 'command', 'name': 'Hello', 'sync': 1, 'opts': {}},\n")
+
+	if err := RewriteManifest(path, "client", block); err != nil {
+		t.Fatal(err)
+	}
+	first, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RewriteManifest(path, "client", block); err != nil {
+		t.Fatal(err)
+	}
+	second, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(first) != string(second) {
+		t.Errorf("rewriting the same block changed the file:\nfirst:  %q\nsecond: %q", first, second)
+	}
+}
+
+func TestLockManifestTimesOutOnHeldLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plugin.vim")
+	lockPath := path + ".lock"
+	if err := os.WriteFile(lockPath, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(lockPath)
+
+	// staleAfter is long relative to timeout, so this only exercises the
+	// "another live holder has it" path, not reclamation.
+	if _, err := lockManifestWithOptions(path, 50*time.Millisecond, time.Hour); err == nil {
+		t.Error("lockManifestWithOptions() with a freshly held lock = nil error, want a timeout error")
+	}
+}
+
+func TestLockManifestReclaimsStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plugin.vim")
+	lockPath := path + ".lock"
+	if err := os.WriteFile(lockPath, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := lockManifestWithOptions(path, time.Second, time.Minute)
+	if err != nil {
+		t.Fatalf("lockManifestWithOptions() on a stale lock = %v, want it to be reclaimed", err)
+	}
+	if err := unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The manifest itself must be writable again once the stale lock is
+	// gone, not just the lock acquisition call.
+	if err := RewriteManifest(path, "client", []byte("\\ {'type': 'command', 'name': 'Hello', 'sync': 1, 'opts': {}},\n")); err != nil {
+		t.Fatalf("RewriteManifest() after reclaiming a stale lock = %v, want it to succeed", err)
+	}
+}
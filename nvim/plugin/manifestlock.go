@@ -0,0 +1,79 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// manifestLockTimeout bounds how long RewriteManifest waits to acquire
+// the lock on a manifest file before giving up.
+const manifestLockTimeout = 5 * time.Second
+
+// manifestLockStaleAfter is how long a lock file may sit unrefreshed
+// before a waiter assumes its holder died and steals it. This keeps a
+// process that's killed or panics mid-rewrite from wedging every
+// future RewriteManifest call on that path forever.
+const manifestLockStaleAfter = 30 * time.Second
+
+// lockManifest serializes concurrent RewriteManifest calls against the
+// same path by creating a sibling lock file with O_EXCL, the same
+// technique portable across platforms without cgo or a syscall
+// package. Without it, two hosts sharing a manifest file can each
+// read-modify-write the whole file independently and the rename that
+// lands second silently discards the other host's block.
+func lockManifest(path string) (unlock func() error, err error) {
+	return lockManifestWithOptions(path, manifestLockTimeout, manifestLockStaleAfter)
+}
+
+func lockManifestWithOptions(path string, timeout, staleAfter time.Duration) (unlock func() error, err error) {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			done := make(chan struct{})
+			go heartbeatLock(lockPath, staleAfter, done)
+			return func() error {
+				close(done)
+				return os.Remove(lockPath)
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > staleAfter {
+			// The holder is presumed dead (killed, panicked before
+			// unlock ran): steal the lock rather than waiting on it
+			// forever. A concurrent waiter may race us to remove it;
+			// whichever of us wins the next O_EXCL create proceeds. A
+			// live holder's lock never reaches this age, since
+			// heartbeatLock keeps refreshing its mtime until unlock.
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("plugin: timed out waiting for lock on %s", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// heartbeatLock refreshes lockPath's mtime well before staleAfter
+// elapses, for as long as its holder keeps the lock. Without this, a
+// holder that's simply slow (a large manifest write, a loaded host
+// process) rather than dead would have its lock reach staleAfter and
+// get stolen by a waiter while still legitimately holding it.
+func heartbeatLock(lockPath string, staleAfter time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(staleAfter / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			os.Chtimes(lockPath, now, now)
+		}
+	}
+}
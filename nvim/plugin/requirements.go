@@ -0,0 +1,77 @@
+package plugin
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// RequirementKind classifies a single entry in the requirement
+// registry, so a loader knows how to check it against the running
+// Neovim instance.
+type RequirementKind string
+
+// Requirement kinds understood by the loader.
+const (
+	// NvimVersion requires a minimum Neovim version, e.g. "0.9.0".
+	NvimVersion RequirementKind = "nvim_version"
+	// Feature requires a :h has() feature, e.g. "nvim-0.9".
+	Feature RequirementKind = "feature"
+	// Command requires an external command to be on PATH, e.g. "rg".
+	Command RequirementKind = "command"
+	// Resource requires a companion Lua or Vimscript file to be present
+	// on the runtimepath, e.g. "lua/myplugin/init.lua".
+	Resource RequirementKind = "resource"
+)
+
+// Requirement is a single entry registered with RegisterRequirement.
+type Requirement struct {
+	Name    string          `json:"name"`
+	Version string          `json:"version,omitempty"`
+	Kind    RequirementKind `json:"kind"`
+}
+
+// Requirements is the JSON document Main writes to stdout when invoked
+// with --requirements or NVIM_GO_PLUGIN_REQUIREMENTS=1. Package managers
+// can read this document at install time to verify the host Neovim
+// satisfies the plugin before ever starting it.
+type Requirements struct {
+	Requirements []Requirement `json:"requirements"`
+}
+
+var (
+	requirementsMu sync.Mutex
+	requirements   []Requirement
+)
+
+// RegisterRequirement records a requirement the plugin needs from its
+// environment. Call it from an init function, before Main runs, the
+// same way plugin handlers are expected to be registered ahead of
+// time.
+func RegisterRequirement(name, version string, kind RequirementKind) {
+	requirementsMu.Lock()
+	defer requirementsMu.Unlock()
+	requirements = append(requirements, Requirement{Name: name, Version: version, Kind: kind})
+}
+
+// dumpRequirements renders the registered requirements as the JSON
+// document described by Requirements.
+func dumpRequirements() ([]byte, error) {
+	requirementsMu.Lock()
+	defer requirementsMu.Unlock()
+	doc := Requirements{Requirements: requirements}
+	if doc.Requirements == nil {
+		doc.Requirements = []Requirement{}
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// LoadRequirements reads a Requirements document previously written by
+// a plugin invoked with --requirements, for use by install-time
+// tooling that wants to verify a host before running it.
+func LoadRequirements(data []byte) (*Requirements, error) {
+	var doc Requirements
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
@@ -0,0 +1,37 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+)
+
+// rpcLogf adapts a Logger to the func(string, ...interface{}) signature
+// nvim.New expects for logging RPC traces: like log.Printf, format and
+// args are a printf-style pair, substituted with fmt.Sprintf so the
+// rendered message matches what log.Printf would have produced. Any
+// error or time.Duration among args is additionally surfaced as a
+// structured field ("error", "duration") a backend like zerolog, hclog
+// or zap can filter or index on, and the presence of an error promotes
+// the line from Debug to Error.
+func rpcLogf(logger Logger) func(string, ...interface{}) {
+	return func(format string, args ...interface{}) {
+		msg := fmt.Sprintf(format, args...)
+
+		var keyvals []interface{}
+		var haveError bool
+		for _, arg := range args {
+			switch v := arg.(type) {
+			case error:
+				keyvals = append(keyvals, "error", v.Error())
+				haveError = true
+			case time.Duration:
+				keyvals = append(keyvals, "duration", v.String())
+			}
+		}
+		if haveError {
+			logger.Error(msg, keyvals...)
+			return
+		}
+		logger.Debug(msg, keyvals...)
+	}
+}
@@ -0,0 +1,64 @@
+package plugin
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type recordingLogger struct {
+	level   string
+	event   string
+	keyvals []interface{}
+}
+
+func (l *recordingLogger) Debug(msg string, keyvals ...interface{}) { l.record("debug", msg, keyvals) }
+func (l *recordingLogger) Info(msg string, keyvals ...interface{})  { l.record("info", msg, keyvals) }
+func (l *recordingLogger) Warn(msg string, keyvals ...interface{})  { l.record("warn", msg, keyvals) }
+func (l *recordingLogger) Error(msg string, keyvals ...interface{}) { l.record("error", msg, keyvals) }
+
+func (l *recordingLogger) record(level, msg string, keyvals []interface{}) {
+	l.level, l.event, l.keyvals = level, msg, keyvals
+}
+
+func (l *recordingLogger) value(key string) (interface{}, bool) {
+	for i := 0; i+1 < len(l.keyvals); i += 2 {
+		if l.keyvals[i] == key {
+			return l.keyvals[i+1], true
+		}
+	}
+	return nil, false
+}
+
+func TestRPCLogfRendersFormat(t *testing.T) {
+	l := &recordingLogger{}
+	logf := rpcLogf(l)
+
+	logf("%s call took %s", "Hello", 120*time.Millisecond)
+	if l.level != "debug" {
+		t.Errorf("level = %q, want debug", l.level)
+	}
+	want := "Hello call took 120ms"
+	if l.event != want {
+		t.Errorf("message = %q, want %q", l.event, want)
+	}
+	if duration, _ := l.value("duration"); duration != (120 * time.Millisecond).String() {
+		t.Errorf("duration = %v, want %s", duration, (120 * time.Millisecond).String())
+	}
+}
+
+func TestRPCLogfPromotesErrors(t *testing.T) {
+	l := &recordingLogger{}
+	logf := rpcLogf(l)
+
+	logf("%s: %v", "Hello", errors.New("boom"))
+	if l.level != "error" {
+		t.Errorf("level = %q, want error", l.level)
+	}
+	if want := "Hello: boom"; l.event != want {
+		t.Errorf("message = %q, want %q", l.event, want)
+	}
+	if msg, _ := l.value("error"); msg != "boom" {
+		t.Errorf("error = %v, want boom", msg)
+	}
+}
@@ -0,0 +1,99 @@
+package plugin
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Spec describes one command, autocmd or function handler a Plugin has
+// registered, in structured form rather than as rendered Vimscript.
+type Spec struct {
+	Type string // "command", "autocmd" or "function"
+	Name string
+	Sync bool
+	// Opts is the raw Vimscript dict literal for this handler's extra
+	// options, e.g. "{'nargs': '*', 'range': ''}" for a command or
+	// "{'pattern': '*.go', 'group': 'GoClient'}" for an autocmd. It is
+	// preserved verbatim from the manifest rather than narrowed to a
+	// few known keys, since any dropped key (nargs, range, complete,
+	// pattern, group, eval, ...) silently changes how the handler
+	// registers with Nvim.
+	Opts string
+}
+
+// specPattern matches one handler entry in the Vimscript Plugin.Manifest
+// renders, e.g.
+//
+//	\ {'type': 'function', 'name': 'Foo', 'sync': 1, 'opts': {'nargs': '*'}},
+//
+// This is the only introspection available until Plugin exposes its
+// handler registry directly; callers needing Specs should prefer a
+// direct accessor on Plugin once one exists. Keeping a single shared
+// pattern (rather than one copy per caller) means a change to this
+// assumption only needs fixing in one place, and specsFromManifest has
+// a test pinning it against a known-good sample. The opts dict is
+// assumed to be a single flat level, which is all Plugin.Manifest has
+// ever rendered.
+var specPattern = regexp.MustCompile(`\{'type': '(\w+)', 'name': '([^']*)', 'sync': (\d), 'opts': (\{[^{}]*\})\}`)
+
+// specsFromManifest extracts the Specs encoded in manifest, the
+// Vimscript text rendered by Plugin.Manifest.
+func specsFromManifest(manifest []byte) []Spec {
+	var specs []Spec
+	for _, match := range specPattern.FindAllStringSubmatch(string(manifest), -1) {
+		specs = append(specs, Spec{
+			Type: match[1],
+			Name: match[2],
+			Sync: match[3] == "1",
+			Opts: match[4],
+		})
+	}
+	return specs
+}
+
+// vimDict renders s the way Nvim's specs RPC expects a single handler
+// entry: a plain map so the msgpack-rpc layer encodes it as a Vim
+// dictionary, not a string. Opts is parsed rather than dropped, so a
+// command's nargs/range/complete or an autocmd's pattern/group/eval
+// survive into the remote#host#RegisterPlugin call :UpdateRemotePlugins
+// writes.
+func (s Spec) vimDict() map[string]interface{} {
+	return map[string]interface{}{
+		"type": s.Type,
+		"name": s.Name,
+		"sync": s.Sync,
+		"opts": parseVimDict(s.Opts),
+	}
+}
+
+// vimDictPairPattern matches one 'key': value pair inside a flat
+// Vimscript dict literal. value is either a single-quoted string
+// (a doubled single quote escapes an embedded quote), an integer, or a
+// v:true/v:false literal.
+var vimDictPairPattern = regexp.MustCompile(`'([^']+)':\s*('(?:[^']|'')*'|-?\d+|v:true|v:false)`)
+
+// parseVimDict parses a flat Vimscript dict literal, e.g.
+// "{'nargs': '*', 'bang': v:true}", into a Go map suitable for msgpack
+// encoding back to Nvim as a dictionary.
+func parseVimDict(raw string) map[string]interface{} {
+	opts := map[string]interface{}{}
+	for _, match := range vimDictPairPattern.FindAllStringSubmatch(raw, -1) {
+		key, value := match[1], match[2]
+		switch {
+		case strings.HasPrefix(value, "'"):
+			opts[key] = strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+		case value == "v:true":
+			opts[key] = true
+		case value == "v:false":
+			opts[key] = false
+		default:
+			if n, err := strconv.Atoi(value); err == nil {
+				opts[key] = n
+			} else {
+				opts[key] = value
+			}
+		}
+	}
+	return opts
+}
@@ -0,0 +1,83 @@
+package plugin
+
+import "testing"
+
+func TestSpecsFromManifest(t *testing.T) {
+	manifest := []byte(`call remote#host#RegisterPlugin('client', '0', [
+      \ {'type': 'command', 'name': 'Hello', 'sync': 1, 'opts': {'nargs': '*', 'range': ''}},
+      \ {'type': 'function', 'name': 'World', 'sync': 0, 'opts': {}},
+      \ {'type': 'autocmd', 'name': 'BufEnter', 'sync': 1, 'opts': {'pattern': '*.go', 'group': 'GoClient'}},
+      \ ])
+`)
+
+	got := specsFromManifest(manifest)
+	want := []Spec{
+		{Type: "command", Name: "Hello", Sync: true, Opts: "{'nargs': '*', 'range': ''}"},
+		{Type: "function", Name: "World", Sync: false, Opts: "{}"},
+		{Type: "autocmd", Name: "BufEnter", Sync: true, Opts: "{'pattern': '*.go', 'group': 'GoClient'}"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("specsFromManifest() returned %d specs, want %d: %+v", len(got), len(want), got)
+	}
+	for i, spec := range got {
+		if spec != want[i] {
+			t.Errorf("spec %d = %+v, want %+v", i, spec, want[i])
+		}
+	}
+}
+
+func TestSpecVimDict(t *testing.T) {
+	got := Spec{Type: "function", Name: "Foo", Sync: true, Opts: "{}"}.vimDict()
+	if got["type"] != "function" || got["name"] != "Foo" || got["sync"] != true {
+		t.Errorf("vimDict() = %+v, want type=function name=Foo sync=true", got)
+	}
+	if _, ok := got["opts"].(map[string]interface{}); !ok {
+		t.Errorf("vimDict()[\"opts\"] = %#v, want a map", got["opts"])
+	}
+}
+
+func TestSpecVimDictPreservesOpts(t *testing.T) {
+	got := Spec{
+		Type: "command",
+		Name: "Hello",
+		Sync: true,
+		Opts: "{'nargs': '*', 'range': '%', 'bang': v:true}",
+	}.vimDict()
+
+	opts, ok := got["opts"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("vimDict()[\"opts\"] = %#v, want a map", got["opts"])
+	}
+	want := map[string]interface{}{"nargs": "*", "range": "%", "bang": true}
+	for k, v := range want {
+		if opts[k] != v {
+			t.Errorf("vimDict()[\"opts\"][%q] = %#v, want %#v", k, opts[k], v)
+		}
+	}
+}
+
+func TestParseVimDict(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want map[string]interface{}
+	}{
+		{"{}", map[string]interface{}{}},
+		{"{'nargs': '*'}", map[string]interface{}{"nargs": "*"}},
+		{"{'complete': 'file', 'bang': v:false}", map[string]interface{}{"complete": "file", "bang": false}},
+		{"{'count': 5}", map[string]interface{}{"count": 5}},
+		{"{'name': 'it''s here'}", map[string]interface{}{"name": "it's here"}},
+	}
+	for _, tt := range tests {
+		got := parseVimDict(tt.raw)
+		if len(got) != len(tt.want) {
+			t.Errorf("parseVimDict(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			continue
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("parseVimDict(%q)[%q] = %#v, want %#v", tt.raw, k, got[k], v)
+			}
+		}
+	}
+}